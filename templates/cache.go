@@ -0,0 +1,220 @@
+package templates
+
+import (
+	"container/list"
+	"encoding/json"
+	"hash/fnv"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache memoizes parsed templates (by path and modification time) and
+// rendered fragments (by template and a hash of the render context), so
+// rebuilding a site doesn't re-parse or re-render the same layout for
+// every post. Eviction is LRU, bounded by a soft memory ceiling: on each
+// insert, entries are evicted from the back of the list until the cache's
+// own tracked size is under the ceiling. The process's heap is checked
+// against the ceiling too, but only on every heapSampleEvery-th insert.
+type Cache struct {
+	ceiling uint64
+
+	mu        sync.Mutex
+	parsed    map[string]parsedEntry
+	fragments map[string]*list.Element
+	order     *list.List
+	size      int
+	inserts   int
+
+	hits, misses, evictions int
+}
+
+// heapSampleEvery bounds how often insert pays for a runtime.ReadMemStats
+// call: that call briefly stops the world, so checking it on every insert
+// would reintroduce the cost the cache exists to avoid. Sampling it every
+// few inserts still catches heap growth quickly enough to evict towards,
+// since each insert's own size is bounded by one rendered fragment.
+const heapSampleEvery = 32
+
+type parsedEntry struct {
+	templ *Template
+	mtime time.Time
+}
+
+type fragmentEntry struct {
+	key  string
+	data []byte
+}
+
+// NewCache builds a Cache with its memory ceiling set to 1/4 of system
+// memory, or to JORGE_MEMORYLIMIT gigabytes if that env var is set.
+func NewCache() *Cache {
+	return &Cache{
+		ceiling:   memoryCeiling(),
+		parsed:    make(map[string]parsedEntry),
+		fragments: make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Parse behaves like the package-level Parse, but returns the cached
+// Template when path hasn't changed on disk since it was last parsed.
+func (c *Cache) Parse(path string) (*Template, error) {
+	if cacheDisabled {
+		return Parse(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.parsed[path]
+	c.mu.Unlock()
+	if ok && entry.mtime.Equal(info.ModTime()) {
+		return entry.templ, nil
+	}
+
+	templ, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.parsed[path] = parsedEntry{templ: templ, mtime: info.ModTime()}
+	c.mu.Unlock()
+	return templ, nil
+}
+
+// GetOrRender returns the cached rendering of templ for ctx, calling fn to
+// produce (and cache) it on a miss.
+func (c *Cache) GetOrRender(templ *Template, ctx map[string]interface{}, fn func() ([]byte, error)) ([]byte, error) {
+	if cacheDisabled {
+		return fn()
+	}
+
+	key := templ.Path + "#" + hashContext(ctx)
+
+	c.mu.Lock()
+	if el, ok := c.fragments[key]; ok {
+		c.order.MoveToFront(el)
+		c.hits++
+		data := el.Value.(*fragmentEntry).data
+		c.mu.Unlock()
+		return data, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	data, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	c.insert(key, data)
+	return data, nil
+}
+
+func (c *Cache) insert(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el := c.order.PushFront(&fragmentEntry{key: key, data: data})
+	c.fragments[key] = el
+	c.size += len(data)
+	c.inserts++
+
+	sampleHeap := c.inserts%heapSampleEvery == 0
+	for c.size > 0 && (uint64(c.size) > c.ceiling || (sampleHeap && heapAlloc() > c.ceiling)) {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		old := back.Value.(*fragmentEntry)
+		c.order.Remove(back)
+		delete(c.fragments, old.key)
+		c.size -= len(old.data)
+		c.evictions++
+	}
+}
+
+// Stats reports cumulative hits, misses and evictions, for benchmarking.
+type Stats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
+
+func heapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// hashContext produces a stable fnv64a hash of ctx by sorting its keys and
+// hashing a canonical JSON encoding of the resulting (key, value) pairs.
+func hashContext(ctx map[string]interface{}) string {
+	keys := make([]string, 0, len(ctx))
+	for k := range ctx {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	type pair struct {
+		K string      `json:"k"`
+		V interface{} `json:"v"`
+	}
+	pairs := make([]pair, len(keys))
+	for i, k := range keys {
+		pairs[i] = pair{K: k, V: ctx[k]}
+	}
+
+	h := fnv.New64a()
+	_ = json.NewEncoder(h).Encode(pairs)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+const defaultMemoryCeiling = 4 << 30 // used when /proc/meminfo isn't available, e.g. on non-Linux
+
+// memoryCeiling is the soft limit the cache evicts towards: JORGE_MEMORYLIMIT
+// (gigabytes) if set, otherwise a quarter of total system memory.
+func memoryCeiling() uint64 {
+	if v := os.Getenv("JORGE_MEMORYLIMIT"); v != "" {
+		if gb, err := strconv.ParseFloat(v, 64); err == nil && gb > 0 {
+			return uint64(gb * (1 << 30))
+		}
+	}
+	return totalSystemMemory() / 4
+}
+
+func totalSystemMemory() uint64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return defaultMemoryCeiling
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			break
+		}
+		return kb * 1024
+	}
+
+	return defaultMemoryCeiling
+}
@@ -0,0 +1,5 @@
+//go:build jorge_nocache
+
+package templates
+
+const cacheDisabled = true
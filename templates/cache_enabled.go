@@ -0,0 +1,7 @@
+//go:build !jorge_nocache
+
+package templates
+
+// cacheDisabled short-circuits Cache to a pass-through when built with
+// `-tags jorge_nocache`, so deterministic test runs can skip memoization.
+const cacheDisabled = false
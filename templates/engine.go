@@ -0,0 +1,45 @@
+package templates
+
+import "path/filepath"
+
+// RenderEngine renders a template body against a context. The site
+// generator picks one per template, by file extension or by an explicit
+// `engine:` front-matter key, so e.g. a Handlebars base layout can wrap
+// Liquid posts.
+//
+// Named RenderEngine rather than Engine because Engine is already the
+// front-matter format enum (YAML/TOML/JSON) on Template.
+type RenderEngine interface {
+	Name() string
+	Extensions() []string
+	Render(source []byte, ctx map[string]interface{}) ([]byte, error)
+}
+
+var (
+	enginesByName      = map[string]RenderEngine{}
+	enginesByExtension = map[string]RenderEngine{}
+)
+
+// RegisterRenderEngine makes e selectable by its Name() or by any of the
+// extensions it claims. A later registration for the same name or
+// extension takes priority, so a site can override a built-in engine.
+func RegisterRenderEngine(e RenderEngine) {
+	enginesByName[e.Name()] = e
+	for _, ext := range e.Extensions() {
+		enginesByExtension[ext] = e
+	}
+}
+
+// engineFor picks the RenderEngine for t: an explicit `engine:` front
+// matter key wins, then the file extension, then Liquid as the default.
+func engineFor(t *Template) RenderEngine {
+	if name, ok := t.Metadata["engine"].(string); ok {
+		if e, ok := enginesByName[name]; ok {
+			return e
+		}
+	}
+	if e, ok := enginesByExtension[filepath.Ext(t.Path)]; ok {
+		return e
+	}
+	return enginesByName["liquid"]
+}
@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Phase identifies which stage of handling a template an error occurred
+// in.
+type Phase string
+
+const (
+	ParsePhase  Phase = "parse"
+	RenderPhase Phase = "render"
+)
+
+// FileError reports a problem parsing or rendering a specific source
+// file, with a line/column when the underlying engine provides one. When
+// a layout fails while wrapping a page, Chain records how it was reached:
+// the page's path followed by the paths of the layouts rendered before
+// the failing one, in render order.
+type FileError struct {
+	Path   string
+	Line   int
+	Column int
+	Engine string
+	Phase  Phase
+	Cause  error
+
+	Chain []string
+
+	source []byte
+}
+
+func (e *FileError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+	}
+	for _, p := range e.Chain {
+		b.WriteString(" -> ")
+		b.WriteString(p)
+	}
+	fmt.Fprintf(&b, ": %v", e.Cause)
+
+	if excerpt := e.excerpt(); excerpt != "" {
+		b.WriteString("\n")
+		b.WriteString(excerpt)
+	}
+	return b.String()
+}
+
+func (e *FileError) Unwrap() error { return e.Cause }
+
+// excerpt renders the offending source line, with a caret under the
+// reported column when one is available.
+func (e *FileError) excerpt() string {
+	if e.Line <= 0 || len(e.source) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(string(e.source), "\n")
+	if e.Line > len(lines) {
+		return ""
+	}
+	line := lines[e.Line-1]
+
+	if e.Column <= 0 {
+		return line
+	}
+	return line + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
+
+// extractLine best-effort recovers a 1-based line number from a render
+// engine's error, duck-typing the handful of accessor method names Liquid
+// and Org's error types expose, without depending on either library's
+// concrete error types directly.
+func extractLine(err error) int {
+	switch e := err.(type) {
+	case interface{ LineNumber() int }:
+		return e.LineNumber()
+	case interface{ Line() int }:
+		return e.Line()
+	default:
+		return 0
+	}
+}
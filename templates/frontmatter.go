@@ -0,0 +1,184 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Engine identifies the serialization format a template's front matter is
+// written in.
+type Engine int
+
+const (
+	// NoFrontMatter means the file has no recognized front matter block.
+	NoFrontMatter Engine = iota
+	YAML
+	TOML
+	JSON
+)
+
+func (e Engine) String() string {
+	switch e {
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	case JSON:
+		return "json"
+	default:
+		return "none"
+	}
+}
+
+type fence struct {
+	engine Engine
+	delim  string
+}
+
+var fences = []fence{
+	{YAML, "---"},
+	{TOML, "+++"},
+}
+
+// forcedEngine lets a file force its front matter format via a secondary
+// extension hint, e.g. "about.toml.md", so it can be parsed unambiguously
+// even if sniffing the content would otherwise guess wrong.
+func forcedEngine(path string) Engine {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	case ".json":
+		return JSON
+	default:
+		return NoFrontMatter
+	}
+}
+
+// splitFrontMatter looks for a recognized front matter fence (YAML's or
+// TOML's `---`/`+++`, or a leading JSON object) at the very start of raw,
+// decodes it, and returns the remaining body. hint, when not
+// NoFrontMatter, forces which decoder to use instead of sniffing the
+// fence. Anything unrecognized is returned as NoFrontMatter with raw
+// untouched, so the caller can treat the file as STATIC. Decode failures
+// are reported as a *FileError scoped to path.
+func splitFrontMatter(path string, raw []byte, hint Engine) (map[string]interface{}, Engine, []byte, error) {
+	if hint == JSON || (hint == NoFrontMatter && bytes.HasPrefix(raw, []byte("{"))) {
+		return splitJSONFrontMatter(path, raw)
+	}
+
+	for _, f := range fences {
+		open := []byte(f.delim + "\n")
+		if !bytes.HasPrefix(raw, open) {
+			continue
+		}
+
+		rest := raw[len(open):]
+		end := bytes.Index(rest, []byte("\n"+f.delim+"\n"))
+		if end < 0 {
+			return nil, NoFrontMatter, nil, fmt.Errorf("front matter not closed")
+		}
+
+		block := rest[:end+1]
+		body := rest[end+len(f.delim)+2:]
+
+		engine := f.engine
+		if hint != NoFrontMatter {
+			engine = hint
+		}
+
+		meta, err := decode(path, engine, block)
+		if err != nil {
+			return nil, NoFrontMatter, nil, err
+		}
+		return meta, engine, body, nil
+	}
+
+	return nil, NoFrontMatter, raw, nil
+}
+
+// splitJSONFrontMatter decodes a leading JSON object and returns whatever
+// follows it as the body. Unlike the fenced formats, JSON has no closing
+// delimiter to search for, so json.Decoder's InputOffset tells us where
+// the object ends.
+func splitJSONFrontMatter(path string, raw []byte) (map[string]interface{}, Engine, []byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	var meta map[string]interface{}
+	if err := dec.Decode(&meta); err != nil {
+		return nil, NoFrontMatter, nil, &FileError{
+			Path: path, Line: lineFromError(raw, err), Engine: JSON.String(), Phase: ParsePhase,
+			Cause: fmt.Errorf("invalid json: %w", err), source: raw,
+		}
+	}
+
+	body := raw[dec.InputOffset():]
+	body = bytes.TrimPrefix(body, []byte("\n"))
+	return meta, JSON, body, nil
+}
+
+func decode(path string, engine Engine, block []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	var err error
+
+	switch engine {
+	case YAML:
+		err = yaml.Unmarshal(block, &raw)
+	case TOML:
+		err = toml.Unmarshal(block, &raw)
+	}
+
+	if err != nil {
+		return nil, &FileError{
+			Path: path, Line: lineFromError(block, err), Engine: engine.String(), Phase: ParsePhase,
+			Cause: fmt.Errorf("invalid %s: %w", engine, err), source: block,
+		}
+	}
+
+	meta, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, &FileError{
+			Path: path, Engine: engine.String(), Phase: ParsePhase,
+			Cause: fmt.Errorf("invalid %s: front matter must decode to an object", engine), source: block,
+		}
+	}
+	return meta, nil
+}
+
+var lineRE = regexp.MustCompile(`line (\d+)`)
+
+// lineFromError best-effort extracts a 1-based line number from a
+// decoder's error message (yaml.v3 embeds one), falling back to counting
+// newlines up to a json.SyntaxError's byte offset.
+func lineFromError(src []byte, err error) int {
+	if m := lineRE.FindStringSubmatch(err.Error()); m != nil {
+		if n, convErr := strconv.Atoi(m[1]); convErr == nil {
+			return n
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if ok := asSyntaxError(err, &syntaxErr); ok {
+		return bytes.Count(src[:syntaxErr.Offset], []byte("\n")) + 1
+	}
+
+	return 0
+}
+
+func asSyntaxError(err error, target **json.SyntaxError) bool {
+	if se, ok := err.(*json.SyntaxError); ok {
+		*target = se
+		return true
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package templates
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// GoTemplateEngine renders Go's html/template syntax. It claims no
+// extension by default; opt in per-file with a front matter `engine: go`.
+type GoTemplateEngine struct{}
+
+func (GoTemplateEngine) Name() string { return "go" }
+
+func (GoTemplateEngine) Extensions() []string { return nil }
+
+func (GoTemplateEngine) Render(source []byte, ctx map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New("").Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func init() {
+	RegisterRenderEngine(GoTemplateEngine{})
+}
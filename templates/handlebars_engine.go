@@ -0,0 +1,22 @@
+package templates
+
+import "github.com/aymerick/raymond"
+
+// HandlebarsEngine renders Handlebars templates for `.hbs` files.
+type HandlebarsEngine struct{}
+
+func (HandlebarsEngine) Name() string { return "handlebars" }
+
+func (HandlebarsEngine) Extensions() []string { return []string{".hbs"} }
+
+func (HandlebarsEngine) Render(source []byte, ctx map[string]interface{}) ([]byte, error) {
+	out, err := raymond.Render(string(source), ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func init() {
+	RegisterRenderEngine(HandlebarsEngine{})
+}
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// LayoutResolver resolves a front-matter `layout` name to the chain of
+// layout Templates that should wrap a page's content, following Hugo's
+// lookup order: a section-scoped layout, falling back to `_default`, then
+// to a top-level layout, plus a `baseof` wrapper resolved the same way.
+//
+// Lookup does the actual filesystem (or test-stub) lookup for a single
+// (section, name) pair; LayoutResolver only owns the ordering and caches
+// the results.
+type LayoutResolver struct {
+	Lookup func(section, name string) (*Template, bool)
+
+	cache map[layoutKey]layoutResult
+}
+
+type layoutKey struct{ section, name string }
+
+type layoutResult struct {
+	templ *Template
+	ok    bool
+}
+
+// NewLayoutResolver builds a LayoutResolver backed by lookup.
+func NewLayoutResolver(lookup func(section, name string) (*Template, bool)) *LayoutResolver {
+	return &LayoutResolver{Lookup: lookup, cache: make(map[layoutKey]layoutResult)}
+}
+
+// Resolve returns the layout chain for a template at path declaring the
+// given layout name: the named layout itself (if found), followed by its
+// baseof wrapper (if one exists). Either may be absent, in which case it's
+// omitted from the chain.
+func (r *LayoutResolver) Resolve(path, name string) []*Template {
+	if name == "" {
+		return nil
+	}
+
+	section := sectionOf(path)
+	var chain []*Template
+
+	if templ, ok := r.find(section, name); ok {
+		chain = append(chain, templ)
+	}
+	if base, ok := r.find(section, "baseof"); ok {
+		chain = append(chain, base)
+	}
+
+	return chain
+}
+
+func (r *LayoutResolver) find(section, name string) (*Template, bool) {
+	key := layoutKey{section, name}
+	if cached, ok := r.cache[key]; ok {
+		return cached.templ, cached.ok
+	}
+
+	for _, s := range candidateSections(section) {
+		if templ, ok := r.Lookup(s, name); ok {
+			r.cache[key] = layoutResult{templ, true}
+			return templ, true
+		}
+	}
+
+	r.cache[key] = layoutResult{nil, false}
+	return nil, false
+}
+
+// candidateSections lists, most specific first, the sections to try when
+// resolving a layout: the page's own section, `_default`, then no section
+// at all (a top-level `layouts/<name>.html`).
+func candidateSections(section string) []string {
+	if section == "" {
+		return []string{"_default", ""}
+	}
+	return []string{section, "_default", ""}
+}
+
+// sectionOf extracts the site section a source file belongs to: the first
+// path segment under `src/`, when the file is nested in a subdirectory of
+// it (`src/blog/post.html` -> "blog"; `src/about.html` -> "").
+func sectionOf(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		if part == "src" && i+2 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package templates
+
+import "github.com/osteele/liquid"
+
+// LiquidEngine renders Liquid templates and is jorge's default RenderEngine
+// for `.html`/`.htm` files.
+type LiquidEngine struct{}
+
+var liquidEngine = liquid.NewEngine()
+
+func (LiquidEngine) Name() string { return "liquid" }
+
+func (LiquidEngine) Extensions() []string { return []string{".html", ".htm"} }
+
+func (LiquidEngine) Render(source []byte, ctx map[string]interface{}) ([]byte, error) {
+	return liquidEngine.ParseAndRender(source, ctx)
+}
+
+func init() {
+	RegisterRenderEngine(LiquidEngine{})
+}
@@ -0,0 +1,44 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/niklasfasching/go-org/org"
+)
+
+// OrgEngine renders Org-mode files for `.org` sources. It composes with a
+// downstream content engine (Liquid by default) so a `.org` file can still
+// use `{{ page.title }}`-style interpolation: the source is rendered
+// through Content first, then the result is parsed as Org and converted to
+// HTML.
+type OrgEngine struct {
+	Content RenderEngine
+}
+
+func (o OrgEngine) Name() string { return "org" }
+
+func (o OrgEngine) Extensions() []string { return []string{".org"} }
+
+func (o OrgEngine) Render(source []byte, ctx map[string]interface{}) ([]byte, error) {
+	content := o.Content
+	if content == nil {
+		content = enginesByName["liquid"]
+	}
+
+	interpolated, err := content.Render(source, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := org.New().Parse(bytes.NewReader(interpolated), "")
+	out, err := doc.Write(org.NewHTMLWriter())
+	if err != nil {
+		return nil, fmt.Errorf("org: %w", err)
+	}
+	return []byte(out), nil
+}
+
+func init() {
+	RegisterRenderEngine(OrgEngine{Content: LiquidEngine{}})
+}
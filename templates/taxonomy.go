@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// TaxonomyOrder controls how terms are ordered when exposed to templates.
+type TaxonomyOrder int
+
+const (
+	OrderAlphabetical TaxonomyOrder = iota
+	OrderByCount
+)
+
+// TaxonomyIndex aggregates a site's templates by taxonomy: `tags` and
+// `categories` always, plus any taxonomy named in the taxonomies argument
+// to BuildTaxonomyIndex (e.g. "series", "authors"), matched against a
+// front matter key of the same name.
+type TaxonomyIndex struct {
+	Tags       map[string][]*Template
+	Categories map[string][]*Template
+	Taxonomies map[string]map[string][]*Template
+
+	Order TaxonomyOrder
+}
+
+// BuildTaxonomyIndex groups templates by the string list values of their
+// `tags` and `categories` front matter, plus one taxonomy map per name in
+// taxonomies.
+func BuildTaxonomyIndex(templates []*Template, taxonomies []string, order TaxonomyOrder) *TaxonomyIndex {
+	idx := &TaxonomyIndex{
+		Tags:       map[string][]*Template{},
+		Categories: map[string][]*Template{},
+		Taxonomies: make(map[string]map[string][]*Template, len(taxonomies)),
+		Order:      order,
+	}
+
+	for _, name := range taxonomies {
+		idx.Taxonomies[name] = map[string][]*Template{}
+	}
+
+	for _, templ := range templates {
+		groupByTerm(idx.Tags, templ, "tags")
+		groupByTerm(idx.Categories, templ, "categories")
+		for _, name := range taxonomies {
+			groupByTerm(idx.Taxonomies[name], templ, name)
+		}
+	}
+
+	return idx
+}
+
+// groupByTerm indexes templ under each value of its front matter key,
+// which may be a list (`tags: [...]`) or a single string (`series: ...`).
+func groupByTerm(into map[string][]*Template, templ *Template, key string) {
+	switch value := templ.Metadata[key].(type) {
+	case []interface{}:
+		for _, v := range value {
+			if term, ok := v.(string); ok {
+				into[term] = append(into[term], templ)
+			}
+		}
+	case string:
+		into[value] = append(into[value], templ)
+	}
+}
+
+// Terms returns a taxonomy map's term names ordered per idx.Order:
+// alphabetically, or by descending post count with the name as a stable
+// tiebreaker so builds stay reproducible.
+func (idx *TaxonomyIndex) Terms(taxonomy map[string][]*Template) []string {
+	terms := make([]string, 0, len(taxonomy))
+	for term := range taxonomy {
+		terms = append(terms, term)
+	}
+
+	if idx.Order == OrderByCount {
+		sort.SliceStable(terms, func(i, j int) bool {
+			if len(taxonomy[terms[i]]) != len(taxonomy[terms[j]]) {
+				return len(taxonomy[terms[i]]) > len(taxonomy[terms[j]])
+			}
+			return terms[i] < terms[j]
+		})
+		return terms
+	}
+
+	sort.Strings(terms)
+	return terms
+}
+
+// TaxonomyTerm pairs a term with the templates tagged with it, in the
+// order layouts iterate them.
+type TaxonomyTerm struct {
+	Term      string
+	Templates []*Template
+}
+
+// Context returns the taxonomy data in the shape layouts expect at
+// `site.tags`, `site.categories`, and `site.<name>` for each extra
+// taxonomy.
+func (idx *TaxonomyIndex) Context() map[string]interface{} {
+	site := map[string]interface{}{
+		"tags":       idx.termList(idx.Tags),
+		"categories": idx.termList(idx.Categories),
+	}
+	for name, taxonomy := range idx.Taxonomies {
+		site[name] = idx.termList(taxonomy)
+	}
+	return site
+}
+
+func (idx *TaxonomyIndex) termList(taxonomy map[string][]*Template) []TaxonomyTerm {
+	terms := idx.Terms(taxonomy)
+	list := make([]TaxonomyTerm, len(terms))
+	for i, term := range terms {
+		list[i] = TaxonomyTerm{Term: term, Templates: taxonomy[term]}
+	}
+	return list
+}
+
+// TagPages synthesizes a virtual TAG_PAGE Template per term in taxonomy,
+// at pathPrefix/<term>/index.html, so the site generator can render one
+// through the usual layout resolution (layouts/_default/tag.html, falling
+// back to layouts/tag.html) just like a regular post. A tag page's own
+// body is empty; the layout does the work of iterating `page.posts`.
+func (idx *TaxonomyIndex) TagPages(taxonomy map[string][]*Template, pathPrefix string) []*Template {
+	terms := idx.Terms(taxonomy)
+
+	pages := make([]*Template, len(terms))
+	for i, term := range terms {
+		pages[i] = &Template{
+			Path: filepath.Join(pathPrefix, term, "index.html"),
+			Type: TAG_PAGE,
+			Metadata: map[string]interface{}{
+				"term":   term,
+				"layout": "tag",
+				"posts":  taxonomy[term],
+			},
+		}
+	}
+	return pages
+}
@@ -0,0 +1,205 @@
+// Package templates parses site source files (posts, pages, layouts) that
+// carry front matter metadata and renders their body through the
+// appropriate templating language.
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Type classifies the role a parsed Template plays in a site build.
+type Type int
+
+const (
+	// STATIC templates are copied through unmodified: either they carry no
+	// front matter, or the front matter isn't the first thing in the file.
+	STATIC Type = iota
+	PAGE
+	POST
+	LAYOUT
+	// TAG_PAGE is a virtual template synthesized by TaxonomyIndex.TagPages
+	// for a single taxonomy term; it has no file on disk.
+	TAG_PAGE
+)
+
+func (t Type) String() string {
+	switch t {
+	case PAGE:
+		return "page"
+	case POST:
+		return "post"
+	case LAYOUT:
+		return "layout"
+	case TAG_PAGE:
+		return "tag_page"
+	default:
+		return "static"
+	}
+}
+
+// Template is a single parsed source file: its front matter metadata and
+// its body, ready to be rendered.
+type Template struct {
+	Path     string
+	Type     Type
+	Engine   Engine
+	Metadata map[string]interface{}
+
+	content []byte
+}
+
+// Ext returns the extension the rendered output will have, which for some
+// source languages (e.g. Org) differs from the file's own extension.
+func (t *Template) Ext() string {
+	if filepath.Ext(t.Path) == ".org" {
+		return ".html"
+	}
+	return filepath.Ext(t.Path)
+}
+
+// Parse reads the file at path, splits off its front matter (if any) and
+// returns the resulting Template. Front matter is only recognized when its
+// opening fence is the first thing in the file; anything else, including a
+// file with no front matter at all, is returned as a STATIC template whose
+// body is the file's untouched contents.
+func Parse(path string) (*Template, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	templ := &Template{
+		Path: path,
+		Type: typeOf(path),
+	}
+
+	meta, engine, body, err := splitFrontMatter(path, raw, forcedEngine(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if engine == NoFrontMatter {
+		templ.Type = STATIC
+		templ.content = raw
+		return templ, nil
+	}
+
+	templ.Engine = engine
+	templ.Metadata = meta
+	templ.content = body
+	return templ, nil
+}
+
+// typeOf infers a template's Type from its path, following the same
+// `src/` and `layouts/` directory conventions the site generator uses to
+// lay out a project.
+func typeOf(path string) Type {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		switch part {
+		case "layouts":
+			return LAYOUT
+		case "src":
+			return POST
+		}
+	}
+	return PAGE
+}
+
+// Render executes the template's body and, if its front matter declares a
+// `layout`, wraps the result in that layout. Callers can supply either a
+// `layoutResolver` (a *LayoutResolver, which understands section overrides
+// and baseof wrapping) or a flat `layouts` map in ctx; the template's own
+// metadata is always exposed to the body as `page`.
+func (t *Template) Render(ctx map[string]interface{}) ([]byte, error) {
+	if t.Type == STATIC {
+		return t.content, nil
+	}
+
+	layoutName, _ := t.Metadata["layout"].(string)
+	var chain []*Template
+	if layoutName != "" {
+		chain = t.layoutChain(ctx, layoutName)
+	}
+
+	bodyCtx := mergeContext(ctx)
+	bodyCtx["page"] = t.Metadata
+
+	rendered, err := t.renderContent(bodyCtx)
+	if err != nil {
+		return nil, t.wrapRenderError(err, nil)
+	}
+
+	// reached tracks the path of t and every layout rendered so far, so a
+	// failure further down the chain can report how it was reached
+	// instead of just the failing layout's own path.
+	reached := []string{t.Path}
+	for _, layout := range chain {
+		layoutCtx := map[string]interface{}{
+			"page":    t.Metadata,
+			"layout":  layout.Metadata,
+			"content": string(rendered) + "\n",
+		}
+
+		render := func() ([]byte, error) { return layout.renderContent(layoutCtx) }
+		if cache, ok := ctx["cache"].(*Cache); ok {
+			rendered, err = cache.GetOrRender(layout, layoutCtx, render)
+		} else {
+			rendered, err = render()
+		}
+		if err != nil {
+			return nil, layout.wrapRenderError(err, reached)
+		}
+		reached = append(reached, layout.Path)
+	}
+
+	return rendered, nil
+}
+
+// wrapRenderError turns a raw render engine error into a *FileError scoped
+// to t, unless it already is one (e.g. returned by a custom RenderEngine).
+func (t *Template) wrapRenderError(err error, chain []string) error {
+	if fileErr, ok := err.(*FileError); ok {
+		return fileErr
+	}
+	return &FileError{
+		Path:   t.Path,
+		Line:   extractLine(err),
+		Engine: engineFor(t).Name(),
+		Phase:  RenderPhase,
+		Cause:  err,
+		Chain:  chain,
+		source: t.content,
+	}
+}
+
+// layoutChain resolves the ordered list of layouts that should wrap t's
+// rendered content, from innermost to outermost (e.g. a section layout
+// followed by the baseof it nests inside).
+func (t *Template) layoutChain(ctx map[string]interface{}, layoutName string) []*Template {
+	if resolver, ok := ctx["layoutResolver"].(*LayoutResolver); ok {
+		return resolver.Resolve(t.Path, layoutName)
+	}
+
+	layouts, _ := ctx["layouts"].(map[string]Template)
+	layout, ok := layouts[layoutName]
+	if !ok {
+		return nil
+	}
+	return []*Template{&layout}
+}
+
+// renderContent runs the template body through whichever RenderEngine
+// applies to it (see engineFor).
+func (t *Template) renderContent(ctx map[string]interface{}) ([]byte, error) {
+	return engineFor(t).Render(t.content, ctx)
+}
+
+func mergeContext(ctx map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(ctx)+1)
+	for k, v := range ctx {
+		merged[k] = v
+	}
+	return merged
+}
@@ -1,6 +1,8 @@
 package templates
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -35,11 +37,11 @@ tags: ["software", "web"]
 }
 
 func TestNonTemplate(t *testing.T) {
-	// not identified as front matter, leaving file as is
-	input := `+++
+	// not a recognized fence, leaving file as is
+	input := `~~~
 title: my new post
 subtitle: a blog post
-+++
+~~~
 <p>Hello World!</p>`
 
 	file := newFile("test*.html", input)
@@ -88,6 +90,87 @@ tags: ["software", "web"]
 	defer os.Remove(file.Name())
 	_, err = Parse(file.Name())
 	assert(t, strings.Contains(err.Error(), "invalid yaml"))
+
+	input = `+++
+title = "my new post
++++
+<p>Hello World!</p>`
+
+	file = newFile("test*.html", input)
+	defer os.Remove(file.Name())
+	_, err = Parse(file.Name())
+	assert(t, strings.Contains(err.Error(), "invalid toml"))
+
+	input = `{"title": "my new post"
+<p>Hello World!</p>`
+
+	file = newFile("test*.html", input)
+	defer os.Remove(file.Name())
+	_, err = Parse(file.Name())
+	assert(t, strings.Contains(err.Error(), "invalid json"))
+}
+
+func TestParseTemplateTOML(t *testing.T) {
+	input := `+++
+title = "my new post"
+subtitle = "a blog post"
+tags = ["software", "web"]
++++
+<p>Hello World!</p>
+`
+
+	file := newFile("test*.html", input)
+	defer os.Remove(file.Name())
+
+	templ, err := Parse(file.Name())
+	assertEqual(t, err, nil)
+
+	assertEqual(t, templ.Type, PAGE)
+	assertEqual(t, templ.Engine, TOML)
+	assertEqual(t, templ.Metadata["title"], "my new post")
+	assertEqual(t, templ.Metadata["tags"].([]interface{})[0], "software")
+
+	content, err := templ.Render(nil)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(content), "<p>Hello World!</p>\n")
+}
+
+func TestParseTemplateJSON(t *testing.T) {
+	input := `{
+  "title": "my new post",
+  "subtitle": "a blog post"
+}
+<p>Hello World!</p>
+`
+
+	file := newFile("test*.html", input)
+	defer os.Remove(file.Name())
+
+	templ, err := Parse(file.Name())
+	assertEqual(t, err, nil)
+
+	assertEqual(t, templ.Type, PAGE)
+	assertEqual(t, templ.Engine, JSON)
+	assertEqual(t, templ.Metadata["title"], "my new post")
+
+	content, err := templ.Render(nil)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(content), "<p>Hello World!</p>\n")
+}
+
+func TestParseTemplateForcedEngine(t *testing.T) {
+	// the file's fence looks like YAML, but the ".toml.md" hint forces a
+	// TOML decode, which fails because the block isn't valid TOML.
+	input := `---
+title: my new post
+---
+<p>Hello World!</p>`
+
+	file := newFile("test*.toml.md", input)
+	defer os.Remove(file.Name())
+
+	_, err := Parse(file.Name())
+	assert(t, strings.Contains(err.Error(), "invalid toml"))
 }
 
 func TestRenderLiquid(t *testing.T) {
@@ -218,11 +301,333 @@ date: 2023-12-01
 }
 
 func TestRenderOrgLayout(t *testing.T) {
-	// TODO
+	base := newFile("layouts/base*.html", "---\ntitle: base\n---\n<main>{{ content }}</main>\n")
+	defer os.Remove(base.Name())
+	baseTempl, err := Parse(base.Name())
+	assertEqual(t, err, nil)
+
+	input := `---
+title: my org post
+layout: base
+---
+#+OPTIONS: toc:nil num:nil
+* {{ page.title }}
+- list 1
+`
+
+	file := newFile("test*.org", input)
+	defer os.Remove(file.Name())
+
+	templ, err := Parse(file.Name())
+	assertEqual(t, err, nil)
+
+	ctx := map[string]interface{}{
+		"layouts": map[string]Template{"base": *baseTempl},
+	}
+	content, err := templ.Render(ctx)
+	assertEqual(t, err, nil)
+	expected := `<main><div id="outline-container-headline-1" class="outline-2">
+<h2 id="headline-1">
+my org post
+</h2>
+<div id="outline-text-headline-1" class="outline-text-2">
+<ul>
+<li>list 1</li>
+</ul>
+</div>
+</div>
+
+</main>
+`
+	assertEqual(t, string(content), expected)
+}
+
+// TestRenderEngineMatrix cross-renders a post in each of the Liquid, Go
+// template and Handlebars engines through a layout in each of the same
+// three engines (Org is covered by TestRenderOrgLayout), to make sure
+// engine selection and layout wrapping compose regardless of which engine
+// is on which side.
+func TestRenderEngineMatrix(t *testing.T) {
+	layoutBody := map[string]string{
+		"liquid":     "<main>{{ content }}</main>\n",
+		"go":         "<main>{{ .content }}</main>\n",
+		"handlebars": "<main>{{{content}}}</main>\n",
+	}
+	layoutExt := map[string]string{
+		"liquid":     ".html",
+		"go":         ".html",
+		"handlebars": ".hbs",
+	}
+	layoutFrontMatter := map[string]string{
+		"liquid":     "---\ntitle: layout\n---\n",
+		"go":         "---\nengine: go\n---\n",
+		"handlebars": "---\ntitle: layout\n---\n",
+	}
+
+	postBody := map[string]string{
+		"liquid":     "<p>{{ page.title }}</p>",
+		"go":         "<p>{{ .page.title }}</p>",
+		"handlebars": "<p>{{page.title}}</p>",
+	}
+	postExt := map[string]string{
+		"liquid":     ".html",
+		"go":         ".html",
+		"handlebars": ".hbs",
+	}
+	postFrontMatter := map[string]string{
+		"liquid":     "",
+		"go":         "engine: go\n",
+		"handlebars": "",
+	}
+
+	for layoutName, body := range layoutBody {
+		layoutFile := newFile("layouts/"+layoutName+"*"+layoutExt[layoutName], layoutFrontMatter[layoutName]+body)
+		defer os.Remove(layoutFile.Name())
+		layoutTempl, err := Parse(layoutFile.Name())
+		assertEqual(t, err, nil)
+
+		for postName, postText := range postBody {
+			input := "---\ntitle: hello\nlayout: " + layoutName + "\n" + postFrontMatter[postName] + "---\n" + postText
+			postFile := newFile("test*"+postExt[postName], input)
+			defer os.Remove(postFile.Name())
+
+			templ, err := Parse(postFile.Name())
+			assertEqual(t, err, nil)
+
+			ctx := map[string]interface{}{
+				"layouts": map[string]Template{layoutName: *layoutTempl},
+			}
+			content, err := templ.Render(ctx)
+			assertEqual(t, err, nil)
+			assert(t, strings.Contains(string(content), "hello"))
+		}
+	}
 }
 
 func TestRenderLayoutLayout(t *testing.T) {
-	// TODO
+	single := `---
+title: single layout
+---
+<article>{{ content }}</article>
+`
+	singleFile := newFile("layouts/blog/single*.html", single)
+	defer os.Remove(singleFile.Name())
+	singleTempl, err := Parse(singleFile.Name())
+	assertEqual(t, err, nil)
+
+	base := `---
+title: base layout
+---
+<html><body>{{ content }}</body></html>
+`
+	baseFile := newFile("layouts/_default/baseof*.html", base)
+	defer os.Remove(baseFile.Name())
+	baseTempl, err := Parse(baseFile.Name())
+	assertEqual(t, err, nil)
+
+	layouts := map[string]map[string]*Template{
+		"blog":     {"single": singleTempl},
+		"_default": {"baseof": baseTempl},
+	}
+	resolver := NewLayoutResolver(func(section, name string) (*Template, bool) {
+		templ, ok := layouts[section][name]
+		return templ, ok
+	})
+
+	input := `---
+title: my blog post
+layout: single
+---
+<h1>{{ page.title }}</h1>`
+
+	post := newFile("src/blog/post1*.html", input)
+	defer os.Remove(post.Name())
+
+	templ, err := Parse(post.Name())
+	assertEqual(t, err, nil)
+
+	content, err := templ.Render(map[string]interface{}{"layoutResolver": resolver})
+	assertEqual(t, err, nil)
+
+	expected := "<html><body><article><h1>my blog post</h1>\n</article>\n\n</body></html>\n"
+	assertEqual(t, string(content), expected)
+}
+
+func TestCacheGetOrRender(t *testing.T) {
+	base := newFile("layouts/base*.html", "---\ntitle: base\n---\n<main>{{ content }}</main>\n")
+	defer os.Remove(base.Name())
+	baseTempl, err := Parse(base.Name())
+	assertEqual(t, err, nil)
+
+	cache := NewCache()
+	calls := 0
+	render := func() ([]byte, error) {
+		calls++
+		return []byte("rendered"), nil
+	}
+
+	ctx := map[string]interface{}{"content": "hello"}
+	out, err := cache.GetOrRender(baseTempl, ctx, render)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(out), "rendered")
+
+	out, err = cache.GetOrRender(baseTempl, ctx, render)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(out), "rendered")
+	assertEqual(t, calls, 1)
+
+	out, err = cache.GetOrRender(baseTempl, map[string]interface{}{"content": "goodbye"}, render)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(out), "rendered")
+	assertEqual(t, calls, 2)
+
+	stats := cache.Stats()
+	assertEqual(t, stats.Hits, 1)
+	assertEqual(t, stats.Misses, 2)
+}
+
+func TestCacheParse(t *testing.T) {
+	file := newFile("test*.html", "---\ntitle: v1\n---\n<p>Hello</p>\n")
+	defer os.Remove(file.Name())
+
+	cache := NewCache()
+	first, err := cache.Parse(file.Name())
+	assertEqual(t, err, nil)
+	assertEqual(t, first.Metadata["title"], "v1")
+
+	second, err := cache.Parse(file.Name())
+	assertEqual(t, err, nil)
+	assert(t, first == second)
+}
+
+func TestRenderLiquidLayoutWithCache(t *testing.T) {
+	base := newFile("layouts/base*.html", "---\ntitle: base\n---\n<main>{{ content }}</main>\n")
+	defer os.Remove(base.Name())
+	baseTempl, err := Parse(base.Name())
+	assertEqual(t, err, nil)
+
+	post := newFile("src/post1*.html", "---\ntitle: my post\nlayout: base\n---\n<h1>{{page.title}}</h1>")
+	defer os.Remove(post.Name())
+	templ, err := Parse(post.Name())
+	assertEqual(t, err, nil)
+
+	cache := NewCache()
+	ctx := map[string]interface{}{
+		"layouts": map[string]Template{"base": *baseTempl},
+		"cache":   cache,
+	}
+
+	content, err := templ.Render(ctx)
+	assertEqual(t, err, nil)
+	expected := "<main><h1>my post</h1>\n</main>\n"
+	assertEqual(t, string(content), expected)
+
+	stats := cache.Stats()
+	assertEqual(t, stats.Misses, 1)
+}
+
+func TestFileErrorChainAndExcerpt(t *testing.T) {
+	err := &FileError{
+		Path:   "post.html",
+		Line:   2,
+		Column: 5,
+		Engine: "liquid",
+		Phase:  RenderPhase,
+		Cause:  fmt.Errorf("undefined variable"),
+		Chain:  []string{"layouts/single.html", "layouts/baseof.html"},
+		source: []byte("one\ntwo\nthree"),
+	}
+
+	msg := err.Error()
+	assert(t, strings.Contains(msg, "post.html:2 -> layouts/single.html -> layouts/baseof.html"))
+	assert(t, strings.Contains(msg, "undefined variable"))
+	assert(t, strings.Contains(msg, "two\n    ^"))
+
+	var recovered *FileError
+	assert(t, errors.As(error(err), &recovered))
+	assertEqual(t, recovered.Line, 2)
+}
+
+func TestTaxonomyIndex(t *testing.T) {
+	f1 := newFile("src/post1*.html", `---
+title: post one
+tags: ["software", "web"]
+series: go basics
+---
+body
+`)
+	defer os.Remove(f1.Name())
+	post1, err := Parse(f1.Name())
+	assertEqual(t, err, nil)
+
+	f2 := newFile("src/post2*.html", `---
+title: post two
+tags: ["web"]
+---
+body
+`)
+	defer os.Remove(f2.Name())
+	post2, err := Parse(f2.Name())
+	assertEqual(t, err, nil)
+
+	idx := BuildTaxonomyIndex([]*Template{post1, post2}, []string{"series"}, OrderAlphabetical)
+
+	assertEqual(t, len(idx.Tags["web"]), 2)
+	assertEqual(t, len(idx.Tags["software"]), 1)
+	assertEqual(t, len(idx.Taxonomies["series"]["go basics"]), 1)
+
+	terms := idx.Terms(idx.Tags)
+	assertEqual(t, terms[0], "software")
+	assertEqual(t, terms[1], "web")
+
+	byCount := &TaxonomyIndex{Tags: idx.Tags, Order: OrderByCount}
+	terms = byCount.Terms(byCount.Tags)
+	assertEqual(t, terms[0], "web")
+	assertEqual(t, terms[1], "software")
+
+	site := idx.Context()
+	tags := site["tags"].([]TaxonomyTerm)
+	assertEqual(t, tags[0].Term, "software")
+	assertEqual(t, len(tags[1].Templates), 2)
+}
+
+func TestTaxonomyIndexTagPages(t *testing.T) {
+	f1 := newFile("src/post1*.html", `---
+title: post one
+tags: ["web"]
+---
+body
+`)
+	defer os.Remove(f1.Name())
+	post1, err := Parse(f1.Name())
+	assertEqual(t, err, nil)
+
+	idx := BuildTaxonomyIndex([]*Template{post1}, nil, OrderAlphabetical)
+	pages := idx.TagPages(idx.Tags, "tags")
+
+	assertEqual(t, len(pages), 1)
+	assertEqual(t, pages[0].Type, TAG_PAGE)
+	assertEqual(t, pages[0].Path, filepath.Join("tags", "web", "index.html"))
+	assertEqual(t, pages[0].Metadata["term"], "web")
+	assertEqual(t, pages[0].Metadata["layout"], "tag")
+
+	tagLayout := `---
+title: tag layout
+---
+tag: {{ page.term }}
+{{ content }}
+`
+	layoutFile := newFile("layouts/_default/tag*.html", tagLayout)
+	defer os.Remove(layoutFile.Name())
+	layoutTempl, err := Parse(layoutFile.Name())
+	assertEqual(t, err, nil)
+
+	ctx := map[string]interface{}{
+		"layouts": map[string]Template{"tag": *layoutTempl},
+	}
+	content, err := pages[0].Render(ctx)
+	assertEqual(t, err, nil)
+	assertEqual(t, string(content), "tag: web\n\n\n")
 }
 
 // ------ HELPERS --------